@@ -0,0 +1,148 @@
+package hashmap
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math"
+)
+
+// ContentHash returns a SHA-256 digest of the map's logical contents: every
+// live key/value pair, independent of insertion order and which shard or
+// bucket holds it. Two maps holding the same entries always produce the
+// same digest, so callers can cheaply detect "did anything change since
+// last time" without diffing the whole map.
+//
+// Each entry is digested on its own as SHA256(len(key) || key || len(value)
+// || value), then the per-entry digests are XOR-accumulated so their order
+// doesn't matter. The final digest folds in the accumulator, the entry
+// count (so two maps can't collide just because their XORs cancel out),
+// and a version tag.
+//
+// Keys and values are turned into bytes by a small type-switch over the
+// same primitives keyBytes supports, plus bool and the other numeric
+// widths, falling back to AppendTo([]byte) []byte for user types (see
+// ReflectHasher). A key or value of any other type — a bare struct, map or
+// slice with no AppendTo method — makes ContentHash panic.
+func (hm *HM) ContentHash() [32]byte {
+	var acc [32]byte
+	var count int64
+	for _, sh := range hm.shards {
+		sh.lock.RLock()
+		accumulateTable(&acc, &count, sh.slices)
+		if sh.migrating {
+			accumulateTable(&acc, &count, sh.slicesNew)
+		}
+		sh.lock.RUnlock()
+	}
+	var final = sha256.New()
+	final.Write(acc[:])
+	writeUint64Bytes(final, uint64(count))
+	final.Write([]byte("hashmap-v1"))
+	var digest [32]byte
+	copy(digest[:], final.Sum(nil))
+	return digest
+}
+
+// accumulateTable XORs every live entry's digest in table into acc and
+// counts it. Entries whose TTL has passed are skipped, same as Get/Exists
+// treat them as absent.
+func accumulateTable(acc *[32]byte, count *int64, table []*Pairs) {
+	for _, pairs := range table {
+		for ; pairs != nil; pairs = pairs.next {
+			if pairs.expired() {
+				continue
+			}
+			var entryDigest = hashEntry(pairs.key, pairs.value)
+			for i := range acc {
+				acc[i] ^= entryDigest[i]
+			}
+			*count++
+		}
+	}
+}
+
+func hashEntry(key interface{}, value interface{}) [32]byte {
+	var h = sha256.New()
+	writeLengthPrefixedBytes(h, entryBytes(key))
+	writeLengthPrefixedBytes(h, entryBytes(value))
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// entryBytes returns the byte representation of a key or value for
+// hashEntry. Numeric and bool types contribute their raw little-endian
+// bits, strings and []byte contribute their contents directly, and a type
+// implementing AppendTo([]byte) []byte contributes that output instead —
+// the same escape hatch ReflectHasher gives struct-shaped keys.
+func entryBytes(v interface{}) []byte {
+	if at, ok := v.(appendToer); ok {
+		return at.AppendTo(nil)
+	}
+	switch x := v.(type) {
+	case []byte:
+		return x
+	case string:
+		return []byte(x)
+	case bool:
+		if x {
+			return []byte{1}
+		}
+		return []byte{0}
+	case int:
+		return leUint64Bytes(uint64(x))
+	case int8:
+		return []byte{byte(x)}
+	case int16:
+		return leUint16Bytes(uint16(x))
+	case int32:
+		return leUint32Bytes(uint32(x))
+	case int64:
+		return leUint64Bytes(uint64(x))
+	case uint:
+		return leUint64Bytes(uint64(x))
+	case uint8:
+		return []byte{x}
+	case uint16:
+		return leUint16Bytes(x)
+	case uint32:
+		return leUint32Bytes(x)
+	case uint64:
+		return leUint64Bytes(x)
+	case float32:
+		return leUint32Bytes(math.Float32bits(x))
+	case float64:
+		return leUint64Bytes(math.Float64bits(x))
+	default:
+		panic(errors.New("contenthash: unsupported value type"))
+	}
+}
+
+func leUint16Bytes(x uint16) []byte {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], x)
+	return b[:]
+}
+
+func leUint32Bytes(x uint32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], x)
+	return b[:]
+}
+
+func leUint64Bytes(x uint64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], x)
+	return b[:]
+}
+
+func writeUint64Bytes(h hash.Hash, x uint64) {
+	h.Write(leUint64Bytes(x))
+}
+
+func writeLengthPrefixedBytes(h hash.Hash, b []byte) {
+	writeUint64Bytes(h, uint64(len(b)))
+	h.Write(b)
+}
@@ -0,0 +1,52 @@
+package hashmap
+
+import (
+	"testing"
+	"time"
+)
+
+func TestContentHashOrderIndependent(t *testing.T) {
+	var a = New(16)
+	var b = New(16)
+	for i := 0; i < 50; i++ {
+		a.Put(i, i*i)
+	}
+	for i := 49; i >= 0; i-- {
+		b.Put(i, i*i)
+	}
+
+	if a.ContentHash() != b.ContentHash() {
+		t.Fatal("ContentHash differs between maps built in different insertion order")
+	}
+}
+
+func TestContentHashDetectsChange(t *testing.T) {
+	var hm = New(16)
+	hm.Put("key", "value")
+	var before = hm.ContentHash()
+
+	hm.Put("key", "other")
+	if hm.ContentHash() == before {
+		t.Fatal("ContentHash unchanged after a value update")
+	}
+
+	hm.Put("key", "value")
+	if hm.ContentHash() != before {
+		t.Fatal("ContentHash differs for maps with identical contents")
+	}
+
+	hm.Remove("key")
+	if hm.ContentHash() == before {
+		t.Fatal("ContentHash unchanged after removing the only entry")
+	}
+}
+
+func TestContentHashIgnoresExpiredEntries(t *testing.T) {
+	var empty = New(16)
+	var hm = New(16)
+	hm.PutWithTTL("key", "value", -time.Second)
+
+	if hm.ContentHash() != empty.ContentHash() {
+		t.Fatal("ContentHash should treat an already-expired entry as absent")
+	}
+}
@@ -0,0 +1,226 @@
+// Package generic provides a type-parameterized counterpart to hashmap.HM.
+// Map[K, V] stores keys and values without boxing them in interface{}, so
+// Put/Get avoid the allocation and interface conversion costs hashmap.HM
+// pays on every call. DefaultHasher still type-switches on each key; a
+// Hasher passed to NewWithHasher avoids that as well.
+package generic
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultShardCount = 1 << 4
+const defaultCapacity = 1 << 4
+
+// Hasher hashes a key of type K. Two keys that compare equal with == must
+// hash to the same value.
+type Hasher[K comparable] func(key K) uint64
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+	hash  uint64
+	last  *entry[K, V]
+	next  *entry[K, V]
+}
+
+type shard[K comparable, V any] struct {
+	slices   []*entry[K, V]
+	capacity int
+	mask     uint64
+	count    int64
+	lock     sync.RWMutex
+}
+
+// Map is a sharded, type-parameterized hash map. Like hashmap.HM,
+// Put/Get/Remove/Exists only lock the shard that owns the key.
+type Map[K comparable, V any] struct {
+	hasher     Hasher[K]
+	shards     []*shard[K, V]
+	shardCount int
+	shardShift uint
+}
+
+// New creates a map using DefaultHasher[K] and the default shard count.
+// DefaultHasher panics on first use if K isn't one of its supported types;
+// call NewWithHasher with your own Hasher for other key types.
+func New[K comparable, V any](capacity int) *Map[K, V] {
+	return NewWithHasher[K, V](capacity, DefaultHasher[K]())
+}
+
+// NewWithHasher creates a map with the default shard count using h to hash
+// keys.
+func NewWithHasher[K comparable, V any](capacity int, h Hasher[K]) *Map[K, V] {
+	var shardCount = defaultShardCount
+	var perShardCapacity = capacity / shardCount
+	if perShardCapacity < defaultCapacity {
+		perShardCapacity = defaultCapacity
+	} else {
+		perShardCapacity = 1 << (int(math.Ceil(math.Log2(float64(perShardCapacity)))))
+	}
+	var m = &Map[K, V]{
+		hasher:     h,
+		shards:     make([]*shard[K, V], shardCount),
+		shardCount: shardCount,
+		shardShift: uint(64 - int(math.Log2(float64(shardCount)))),
+	}
+	for i := range m.shards {
+		m.shards[i] = &shard[K, V]{
+			slices:   make([]*entry[K, V], perShardCapacity),
+			capacity: perShardCapacity,
+			mask:     uint64(perShardCapacity - 1),
+		}
+	}
+	return m
+}
+
+func (m *Map[K, V]) Put(key K, value V) {
+	var hash = m.hasher(key)
+	var sh = m.shards[hash>>m.shardShift]
+	var hashIndex = int(hash & sh.mask)
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+	var e = sh.find(hashIndex, hash, key)
+	if e == nil {
+		var newEntry = &entry[K, V]{key: key, value: value, hash: hash}
+		var head = sh.slices[hashIndex]
+		if head != nil {
+			head.last.next = newEntry
+			head.last = newEntry
+		} else {
+			newEntry.last = newEntry
+			sh.slices[hashIndex] = newEntry
+		}
+		atomic.AddInt64(&sh.count, 1)
+	} else {
+		e.value = value
+	}
+}
+
+func (m *Map[K, V]) Get(key K) (V, bool) {
+	var hash = m.hasher(key)
+	var sh = m.shards[hash>>m.shardShift]
+	sh.lock.RLock()
+	defer sh.lock.RUnlock()
+	var e = sh.find(int(hash&sh.mask), hash, key)
+	if e == nil {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+func (m *Map[K, V]) Exists(key K) bool {
+	var hash = m.hasher(key)
+	var sh = m.shards[hash>>m.shardShift]
+	sh.lock.RLock()
+	defer sh.lock.RUnlock()
+	return sh.find(int(hash&sh.mask), hash, key) != nil
+}
+
+func (m *Map[K, V]) Remove(key K) {
+	var hash = m.hasher(key)
+	var sh = m.shards[hash>>m.shardShift]
+	var hashIndex = int(hash & sh.mask)
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+	var prev *entry[K, V]
+	var e = sh.slices[hashIndex]
+	var head = e
+	for e != nil {
+		if e.hash == hash && e.key == key {
+			if prev != nil {
+				prev.next = e.next
+				if e.next == nil {
+					head.last = prev
+				}
+			} else {
+				if e.next != nil {
+					e.next.last = e.last
+				}
+				sh.slices[hashIndex] = e.next
+			}
+			atomic.AddInt64(&sh.count, -1)
+			return
+		}
+		prev = e
+		e = e.next
+	}
+}
+
+// GetCount sums the per-shard atomic counters instead of holding any lock.
+func (m *Map[K, V]) GetCount() int {
+	var total int64
+	for _, sh := range m.shards {
+		total += atomic.LoadInt64(&sh.count)
+	}
+	return int(total)
+}
+
+// Iterate walks the shards one at a time, holding only that shard's read
+// lock at a time.
+func (m *Map[K, V]) Iterate(readFunc func(key K, value V)) {
+	for _, sh := range m.shards {
+		sh.lock.RLock()
+		for _, e := range sh.slices {
+			for e != nil {
+				readFunc(e.key, e.value)
+				e = e.next
+			}
+		}
+		sh.lock.RUnlock()
+	}
+}
+
+func (sh *shard[K, V]) find(hashIndex int, hash uint64, key K) *entry[K, V] {
+	var e = sh.slices[hashIndex]
+	for e != nil {
+		if e.hash == hash && e.key == key {
+			return e
+		}
+		e = e.next
+	}
+	return nil
+}
+
+// mix64 spreads an integer key's low bits across the whole word (Fibonacci
+// hashing) so its top bits are usable for shard selection.
+func mix64(x uint64) uint64 {
+	return x * 0x9E3779B97F4A7C15
+}
+
+// DefaultHasher returns a Hasher for the built-in comparable key types New
+// supports: integers are spread across the word with mix64 and strings are
+// hashed with xxHash64. It panics on first use for any other K.
+func DefaultHasher[K comparable]() Hasher[K] {
+	return func(key K) uint64 {
+		switch k := any(key).(type) {
+		case int:
+			return mix64(uint64(k))
+		case int8:
+			return mix64(uint64(k))
+		case int16:
+			return mix64(uint64(k))
+		case int32:
+			return mix64(uint64(k))
+		case int64:
+			return mix64(uint64(k))
+		case uint:
+			return mix64(uint64(k))
+		case uint8:
+			return mix64(uint64(k))
+		case uint16:
+			return mix64(uint64(k))
+		case uint32:
+			return mix64(uint64(k))
+		case uint64:
+			return mix64(k)
+		case string:
+			return xxhash64([]byte(k), 0)
+		default:
+			panic("generic: no DefaultHasher for this key type; use NewWithHasher")
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package generic
+
+import "testing"
+
+func TestMapPutGetRemove(t *testing.T) {
+	var m = New[int, string](16)
+	const n = 100
+	for i := 0; i < n; i++ {
+		m.Put(i, "v")
+	}
+	if got := m.GetCount(); got != n {
+		t.Fatalf("GetCount() = %d, want %d", got, n)
+	}
+	for i := 0; i < n; i++ {
+		if v, ok := m.Get(i); !ok || v != "v" {
+			t.Fatalf("Get(%d) = (%q, %v), want (%q, true)", i, v, ok, "v")
+		}
+		if !m.Exists(i) {
+			t.Fatalf("Exists(%d) = false, want true", i)
+		}
+	}
+
+	m.Put(1, "updated")
+	if v, _ := m.Get(1); v != "updated" {
+		t.Fatalf("Get(1) after update = %q, want %q", v, "updated")
+	}
+
+	for i := 0; i < n; i += 2 {
+		m.Remove(i)
+	}
+	if got, want := m.GetCount(), n/2; got != want {
+		t.Fatalf("GetCount() after removing evens = %d, want %d", got, want)
+	}
+	for i := 0; i < n; i++ {
+		var wantExists = i%2 != 0
+		if got := m.Exists(i); got != wantExists {
+			t.Fatalf("Exists(%d) = %v, want %v", i, got, wantExists)
+		}
+	}
+}
+
+func TestMapStringKeys(t *testing.T) {
+	var m = New[string, int](16)
+	m.Put("alice", 1)
+	m.Put("bob", 2)
+	if v, ok := m.Get("alice"); !ok || v != 1 {
+		t.Fatalf("Get(%q) = (%d, %v), want (1, true)", "alice", v, ok)
+	}
+	if _, ok := m.Get("carol"); ok {
+		t.Fatalf("Get(%q) = (_, true), want (_, false)", "carol")
+	}
+}
+
+func TestMapIterate(t *testing.T) {
+	var m = New[int, int](16)
+	var want = map[int]int{}
+	for i := 0; i < 20; i++ {
+		m.Put(i, i*i)
+		want[i] = i * i
+	}
+
+	var got = map[int]int{}
+	m.Iterate(func(key int, value int) {
+		got[key] = value
+	})
+
+	if len(got) != len(want) {
+		t.Fatalf("Iterate visited %d entries, want %d", len(got), len(want))
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("Iterate entry %d = %d, want %d", k, got[k], v)
+		}
+	}
+}
+
+func TestDefaultHasherPanicsOnUnsupportedType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected DefaultHasher to panic for an unsupported key type")
+		}
+	}()
+	var m = New[struct{ X int }, int](16)
+	m.Put(struct{ X int }{X: 1}, 1)
+}
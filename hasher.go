@@ -0,0 +1,144 @@
+package hashmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc64"
+	"hash/fnv"
+)
+
+// Hasher hashes and compares keys for an HM. Hash must return the same
+// value for keys that Equal reports as equal. New and NewSharded use
+// FNVHasher; NewWithHasher and NewShardedWithHasher accept any Hasher.
+type Hasher interface {
+	Hash(key interface{}) uint64
+	Equal(a, b interface{}) bool
+}
+
+// mix64 spreads an integer key's low bits across the whole word (Fibonacci
+// hashing) so its top bits are usable for shard selection rather than
+// almost always being zero.
+func mix64(x uint64) uint64 {
+	return x * 0x9E3779B97F4A7C15
+}
+
+// equalByType compares two keys of one of the built-in supported types. It
+// is shared by every Hasher below, since they only differ in how they hash
+// a key, not in how they compare one.
+func equalByType(a, b interface{}) bool {
+	switch a.(type) {
+	case []uint8:
+		return bytesEqual(a, b)
+	case string:
+		return stringEqual(a, b)
+	case int:
+		return intEqual(a, b)
+	case uint32:
+		return uint32Equal(a, b)
+	case uint64:
+		return uint64Equal(a, b)
+	default:
+		panic(errors.New("bad key type"))
+	}
+}
+
+func bytesEqual(v1, v2 interface{}) bool {
+	return bytes.Equal(v1.([]byte), v2.([]byte))
+}
+
+func stringEqual(v1, v2 interface{}) bool {
+	return v1.(string) == v2.(string)
+}
+
+func intEqual(v1, v2 interface{}) bool {
+	return v1.(int) == v2.(int)
+}
+
+func uint32Equal(v1, v2 interface{}) bool {
+	return v1.(uint32) == v2.(uint32)
+}
+
+func uint64Equal(v1, v2 interface{}) bool {
+	return v1.(uint64) == v2.(uint64)
+}
+
+// keyBytes returns the byte representation of a key of one of the built-in
+// supported types, as used by the byte-oriented hashers below.
+func keyBytes(key interface{}) []byte {
+	switch v := key.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	case int:
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], uint64(v))
+		return b[:]
+	case uint32:
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], v)
+		return b[:]
+	case uint64:
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], v)
+		return b[:]
+	default:
+		panic(errors.New("bad key type"))
+	}
+}
+
+// FNVHasher hashes []byte and string keys with FNV-1a (64-bit), and int,
+// uint32 and uint64 keys with mix64. It is the default Hasher used by New
+// and NewSharded.
+type FNVHasher struct{}
+
+func (FNVHasher) Hash(key interface{}) uint64 {
+	switch v := key.(type) {
+	case []uint8:
+		var h = fnv.New64a()
+		h.Write(v)
+		return h.Sum64()
+	case string:
+		var h = fnv.New64a()
+		h.Write([]byte(v))
+		return h.Sum64()
+	case int:
+		return mix64(uint64(v))
+	case uint32:
+		return mix64(uint64(v))
+	case uint64:
+		return mix64(v)
+	default:
+		panic(errors.New("bad key type"))
+	}
+}
+
+func (FNVHasher) Equal(a, b interface{}) bool {
+	return equalByType(a, b)
+}
+
+// XXHasher hashes the same key types as FNVHasher using xxHash64, which
+// trades FNV's simplicity for noticeably faster throughput on longer keys.
+type XXHasher struct{}
+
+func (XXHasher) Hash(key interface{}) uint64 {
+	return xxhash64(keyBytes(key), 0)
+}
+
+func (XXHasher) Equal(a, b interface{}) bool {
+	return equalByType(a, b)
+}
+
+// CRC64Hasher hashes the same key types as FNVHasher using CRC-64/ECMA.
+type CRC64Hasher struct{}
+
+var crc64ECMATable = crc64.MakeTable(crc64.ECMA)
+
+func (CRC64Hasher) Hash(key interface{}) uint64 {
+	return crc64.Checksum(keyBytes(key), crc64ECMATable)
+}
+
+func (CRC64Hasher) Equal(a, b interface{}) bool {
+	return equalByType(a, b)
+}
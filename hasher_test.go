@@ -0,0 +1,50 @@
+package hashmap
+
+import "testing"
+
+func TestFNVHasherRoundTrip(t *testing.T) {
+	testHasherRoundTrip(t, FNVHasher{})
+}
+
+func TestXXHasherRoundTrip(t *testing.T) {
+	testHasherRoundTrip(t, XXHasher{})
+}
+
+func TestCRC64HasherRoundTrip(t *testing.T) {
+	testHasherRoundTrip(t, CRC64Hasher{})
+}
+
+// testHasherRoundTrip checks that h hashes equal keys of every type it
+// supports to the same value, and treats distinct keys as both unequal and
+// (for these small, well-spread cases) differently hashed.
+func testHasherRoundTrip(t *testing.T, h Hasher) {
+	t.Helper()
+	var cases = []struct {
+		name  string
+		a     interface{}
+		b     interface{}
+		other interface{}
+	}{
+		{"bytes", []byte("hello"), []byte("hello"), []byte("world")},
+		{"string", "hello", "hello", "world"},
+		{"int", 42, 42, 43},
+		{"uint32", uint32(42), uint32(42), uint32(43)},
+		{"uint64", uint64(42), uint64(42), uint64(43)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if !h.Equal(c.a, c.b) {
+				t.Fatalf("Equal(%v, %v) = false, want true", c.a, c.b)
+			}
+			if h.Hash(c.a) != h.Hash(c.b) {
+				t.Fatalf("Hash(%v) != Hash(%v), want equal", c.a, c.b)
+			}
+			if h.Equal(c.a, c.other) {
+				t.Fatalf("Equal(%v, %v) = true, want false", c.a, c.other)
+			}
+			if h.Hash(c.a) == h.Hash(c.other) {
+				t.Fatalf("Hash(%v) == Hash(%v), want different", c.a, c.other)
+			}
+		})
+	}
+}
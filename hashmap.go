@@ -1,160 +1,291 @@
 package hashmap
 
 import (
-	"bytes"
-	"errors"
 	"math"
 	"sync"
-
-	"hash/fnv"
+	"sync/atomic"
+	"time"
 )
 
 type IsValidAndUpdataFunc func(key interface{}, value interface{}) bool
 type ReadFunc func(key interface{}, value interface{})
 type UpdateFunc func(value interface{})
-type EqualFunc func(v1, v2 interface{}) bool
 
+const defaultShardCount = 1 << 4
+const defaultLoadFactor = 0.75
+const migrateStepsPerOp = 2
+
+// HM is sharded across a fixed number of independently-locked tables, so
+// Put/Get/Remove/Exists only ever contend with callers touching the same
+// shard instead of the whole map. Keys are hashed and compared by the
+// configured Hasher rather than a hard-coded type switch.
 type HM struct {
+	hasher         Hasher
+	shards         []*shard
+	shardCount     int
+	shardShift     uint
+	loadFactorBits uint64
+
+	// Eviction is opt-in: maxEntries is 0 unless the map was created with
+	// NewLRU, in which case lruLock guards a recency list threaded through
+	// every Pairs node via lruPrev/lruNext, independent of which shard owns
+	// the node.
+	maxEntries int
+	lruLock    sync.Mutex
+	lruHead    *Pairs
+	lruTail    *Pairs
+
+	onEvictLock sync.RWMutex
+	onEvictFn   func(key interface{}, value interface{})
+
+	janitorStarted int32
+	janitorStop    chan struct{}
+}
+
+// shard is one independently-locked bucket table. While resizing, slices is
+// the old table and slicesNew is the table being grown or shrunk into;
+// buckets migrate from one to the other a few at a time, driven by the
+// Put/Get/Remove calls that touch them plus a bounded per-call helper, so no
+// single call ever pays for rehashing the whole shard.
+type shard struct {
 	slices      []*Pairs
 	capacity    int
-	mask_uint32 uint32
-	mask_uint64 uint64
-	mask_int    int
-	count       int
-	lock        *sync.RWMutex
+	mask        uint64
+	slicesNew   []*Pairs
+	capacityNew int
+	maskNew     uint64
+	migrating   bool
+	migrateIdx  int
+	count       int64
+	lock        sync.RWMutex
 }
 
 type Pairs struct {
-	key   interface{}
-	value interface{}
-	last  *Pairs
-	next  *Pairs
+	key     interface{}
+	value   interface{}
+	hash    uint64
+	expiry  int64
+	last    *Pairs
+	next    *Pairs
+	lruPrev *Pairs
+	lruNext *Pairs
 }
 
+// New creates a sharded map using the default shard count and an FNV-based
+// Hasher.
 func New(capacity int) *HM {
+	return NewShardedWithHasher(capacity, defaultShardCount, FNVHasher{})
+}
+
+// NewSharded creates a map with shardCount independently-locked shards and
+// an FNV-based Hasher.
+func NewSharded(capacity int, shardCount int) *HM {
+	return NewShardedWithHasher(capacity, shardCount, FNVHasher{})
+}
+
+// NewWithHasher creates a map using the default shard count and the given
+// Hasher for hashing and comparing keys.
+func NewWithHasher(capacity int, h Hasher) *HM {
+	return NewShardedWithHasher(capacity, defaultShardCount, h)
+}
+
+// NewShardedWithHasher creates a map with shardCount independently-locked
+// shards, each sized to hold roughly capacity/shardCount entries, using h to
+// hash and compare keys. shardCount is rounded up to a power of two so a
+// key's shard can be picked from the top bits of its hash with a plain
+// shift.
+func NewShardedWithHasher(capacity int, shardCount int, h Hasher) *HM {
 	var defaultCapacity = 1 << 4
-	if capacity < defaultCapacity {
-		capacity = defaultCapacity
+	if shardCount < 1 {
+		shardCount = 1
+	} else {
+		shardCount = 1 << (int(math.Ceil(math.Log2(float64(shardCount)))))
+	}
+	var perShardCapacity = capacity / shardCount
+	if perShardCapacity < defaultCapacity {
+		perShardCapacity = defaultCapacity
 	} else {
-		capacity = 1 << (int(math.Ceil(math.Log2(float64(capacity)))))
+		perShardCapacity = 1 << (int(math.Ceil(math.Log2(float64(perShardCapacity)))))
 	}
 	var hm = &HM{
-		slices:      make([]*Pairs, capacity),
-		capacity:    capacity,
-		count:       0,
-		mask_int:    capacity - 1,
-		mask_uint32: uint32(capacity - 1),
-		mask_uint64: uint64(capacity - 1),
-		lock:        &sync.RWMutex{},
+		hasher:         h,
+		shards:         make([]*shard, shardCount),
+		shardCount:     shardCount,
+		shardShift:     uint(64 - int(math.Log2(float64(shardCount)))),
+		loadFactorBits: math.Float64bits(defaultLoadFactor),
+	}
+	for i := range hm.shards {
+		hm.shards[i] = newShard(perShardCapacity)
 	}
 	return hm
 }
 
-func (hm *HM) Expand(capacity int) *HM {
-	if capacity <= hm.count {
-		panic(errors.New("the capacity is less than the number of items in the list"))
-	}
-	var newhm = New(capacity)
-	var firstPairs, pairs *Pairs
-	hm.lock.Lock()
-	defer hm.lock.Unlock()
-	for _, firstPairs = range hm.slices {
-		if firstPairs == nil {
-			continue
-		}
-		pairs = firstPairs
-		for {
-			newhm.Put(pairs.key, pairs.value)
-			if pairs.next != nil {
-				pairs = pairs.next
-			} else {
-				firstPairs.last = pairs
-				break
-			}
-		}
+func newShard(capacity int) *shard {
+	return &shard{
+		slices:   make([]*Pairs, capacity),
+		capacity: capacity,
+		mask:     uint64(capacity - 1),
 	}
-	return newhm
+}
+
+// SetLoadFactor sets the count/capacity ratio a shard must cross before it
+// starts growing. The low-water mark for shrinking is always a quarter of
+// this value.
+func (hm *HM) SetLoadFactor(f float64) {
+	atomic.StoreUint64(&hm.loadFactorBits, math.Float64bits(f))
+}
+
+func (hm *HM) loadFactor() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&hm.loadFactorBits))
 }
 
 func (hm *HM) Get(key interface{}) interface{} {
-	hm.lock.RLock()
-	defer hm.lock.RUnlock()
-	var pairs, _ = hm.getPairsUnsafe(key)
-	if pairs != nil {
-		return pairs.value
+	var hash = hm.hasher.Hash(key)
+	var sh = hm.shards[hash>>hm.shardShift]
+	sh.lock.RLock()
+	if sh.migrating {
+		// Migrating a bucket needs the write lock, so a Get on a resizing
+		// shard briefly trades its read lock for it to move its own bucket.
+		sh.lock.RUnlock()
+		sh.lock.Lock()
+		sh.resolveForWrite(hash)
+		sh.lock.Unlock()
+		sh.lock.RLock()
 	}
-	return nil
+	var pairs = sh.getPairsUnsafe(sh.slices, int(hash&sh.mask), hash, hm.hasher, key)
+	if pairs == nil && sh.migrating {
+		pairs = sh.getPairsUnsafe(sh.slicesNew, int(hash&sh.maskNew), hash, hm.hasher, key)
+	}
+	var found = pairs != nil && !pairs.expired()
+	var value interface{}
+	if found {
+		value = pairs.value
+		hm.touchLRU(pairs)
+	}
+	sh.lock.RUnlock()
+	if !found {
+		return nil
+	}
+	return value
 }
 
 func (hm *HM) Put(key interface{}, value interface{}) {
-	var pairs, hashIndex = hm.getPairs(key)
+	hm.put(key, value, 0)
+}
+
+// PutWithTTL is like Put, but the entry is treated as absent by Get/Exists
+// once ttl has elapsed. A StartJanitor goroutine, if running, reaps it from
+// the map entirely instead of waiting for it to be looked up.
+func (hm *HM) PutWithTTL(key interface{}, value interface{}, ttl time.Duration) {
+	hm.put(key, value, time.Now().Add(ttl).UnixNano())
+}
+
+func (hm *HM) put(key interface{}, value interface{}, expiry int64) {
+	var hash = hm.hasher.Hash(key)
+	var sh = hm.shards[hash>>hm.shardShift]
+	sh.lock.Lock()
+	var table, hashIndex = sh.resolveForWrite(hash)
+	var pairs = sh.getPairsUnsafe(table, hashIndex, hash, hm.hasher, key)
 	if pairs == nil {
-		var newPairs = &Pairs{
-			key:   key,
-			value: value,
-			last:  nil,
-			next:  nil,
+		pairs = &Pairs{
+			key:    key,
+			value:  value,
+			hash:   hash,
+			expiry: expiry,
 		}
-		hm.lock.Lock()
-		defer hm.lock.Unlock()
-		pairs = hm.slices[hashIndex]
-		if pairs != nil {
-			pairs.last.next = newPairs
-			pairs.last = newPairs
-		} else {
-			newPairs.last = newPairs
-			hm.slices[hashIndex] = newPairs
-		}
-		hm.count++
+		appendPair(table, hashIndex, pairs)
+		atomic.AddInt64(&sh.count, 1)
+		sh.maybeGrow(hm.loadFactor())
 	} else {
-		hm.lock.Lock()
-		defer hm.lock.Unlock()
 		pairs.value = value
+		pairs.expiry = expiry
 	}
+	hm.touchLRU(pairs)
+	sh.lock.Unlock()
+	hm.evictIfOverCapacity()
 }
 
-func (hm *HM) UpdateWithFunc(key interface{}, updateFunc UpdateFunc) {
-	hm.lock.Lock()
-	defer hm.lock.Unlock()
-	var pairs, _ = hm.getPairsUnsafe(key)
+// UpdateWithRemoveFunc is called with a key's current value, or nil if it's
+// absent, and a remove closure that deletes that same key. remove is only
+// safe to call from inside this callback: it reuses the shard lock
+// UpdateWithFunc already holds for key instead of taking it again, which is
+// the only way removing a key mid-update can avoid either deadlocking on
+// that lock or racing a concurrent Get/Put on some other key that happens
+// to share the shard.
+type UpdateWithRemoveFunc func(value interface{}, remove func())
+
+func (hm *HM) UpdateWithFunc(key interface{}, updateFunc UpdateWithRemoveFunc) {
+	var hash = hm.hasher.Hash(key)
+	var sh = hm.shards[hash>>hm.shardShift]
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+	var table, hashIndex = sh.resolveForWrite(hash)
+	var pairs = sh.getPairsUnsafe(table, hashIndex, hash, hm.hasher, key)
+	var remove = func() {
+		sh.removePairs(table, hashIndex, hash, hm.hasher, key, nil, hm.onRemoved)
+	}
 	if pairs != nil {
-		updateFunc(pairs.value)
+		updateFunc(pairs.value, remove)
 	} else {
-		updateFunc(nil)
+		updateFunc(nil, remove)
 	}
+	sh.maybeShrink(hm.loadFactor())
 }
 
 func (hm *HM) Remove(key interface{}) {
-	hm.lock.Lock()
-	defer hm.lock.Unlock()
-	hm.removePairs(key, nil)
-}
-
-func (hm *HM) RemoveUnsafe(key interface{}) {
-	hm.removePairs(key, nil)
+	var hash = hm.hasher.Hash(key)
+	var sh = hm.shards[hash>>hm.shardShift]
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+	var table, hashIndex = sh.resolveForWrite(hash)
+	sh.removePairs(table, hashIndex, hash, hm.hasher, key, nil, hm.onRemoved)
+	sh.maybeShrink(hm.loadFactor())
 }
 
 func (hm *HM) RemoveAndUpdate(key interface{}, updateFunc UpdateFunc) {
-	hm.lock.Lock()
-	defer hm.lock.Unlock()
-	hm.removePairs(key, updateFunc)
+	var hash = hm.hasher.Hash(key)
+	var sh = hm.shards[hash>>hm.shardShift]
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+	var table, hashIndex = sh.resolveForWrite(hash)
+	sh.removePairs(table, hashIndex, hash, hm.hasher, key, updateFunc, hm.onRemoved)
+	sh.maybeShrink(hm.loadFactor())
 }
 
 func (hm *HM) Exists(key interface{}) bool {
-	hm.lock.RLock()
-	defer hm.lock.RUnlock()
-	var pairs, _ = hm.getPairsUnsafe(key)
-	if pairs != nil {
-		return true
+	var hash = hm.hasher.Hash(key)
+	var sh = hm.shards[hash>>hm.shardShift]
+	sh.lock.RLock()
+	var pairs = sh.getPairsUnsafe(sh.slices, int(hash&sh.mask), hash, hm.hasher, key)
+	if pairs == nil && sh.migrating {
+		pairs = sh.getPairsUnsafe(sh.slicesNew, int(hash&sh.maskNew), hash, hm.hasher, key)
 	}
-	return false
+	var found = pairs != nil && !pairs.expired()
+	if found {
+		hm.touchLRU(pairs)
+	}
+	sh.lock.RUnlock()
+	return found
 }
 
+// Iterate walks the shards one at a time, holding only that shard's read
+// lock, rather than a single lock across the whole map. A shard that is
+// mid-resize has its buckets split between slices and slicesNew, so both
+// are walked; a bucket never appears in both at once.
 func (hm *HM) Iterate(readFunc ReadFunc) {
+	for _, sh := range hm.shards {
+		sh.lock.RLock()
+		walkTable(sh.slices, readFunc)
+		if sh.migrating {
+			walkTable(sh.slicesNew, readFunc)
+		}
+		sh.lock.RUnlock()
+	}
+}
+
+func walkTable(table []*Pairs, readFunc ReadFunc) {
 	var pairs *Pairs
-	hm.lock.RLock()
-	defer hm.lock.RUnlock()
-	for _, pairs = range hm.slices {
+	for _, pairs = range table {
 		if pairs == nil {
 			continue
 		}
@@ -170,47 +301,158 @@ func (hm *HM) Iterate(readFunc ReadFunc) {
 
 func (hm *HM) IterateAndUpdate(isValidAndUpdateFunc IsValidAndUpdataFunc) {
 	var pairs, nextPairs *Pairs
-	hm.lock.Lock()
-	defer hm.lock.Unlock()
-	for _, pairs = range hm.slices {
-		if pairs == nil {
-			continue
-		}
-		for {
-			nextPairs = pairs.next
-			if isValidAndUpdateFunc(pairs.key, pairs.value) == false {
-				hm.removePairs(pairs.key, nil)
+	for _, sh := range hm.shards {
+		sh.lock.Lock()
+		sh.finishMigration()
+		for _, pairs = range sh.slices {
+			if pairs == nil {
+				continue
 			}
-			if nextPairs == nil {
-				break
+			for {
+				nextPairs = pairs.next
+				if isValidAndUpdateFunc(pairs.key, pairs.value) == false {
+					sh.removePairs(sh.slices, int(pairs.hash&sh.mask), pairs.hash, hm.hasher, pairs.key, nil, hm.onRemoved)
+				}
+				if nextPairs == nil {
+					break
+				}
+				pairs = nextPairs
 			}
-			pairs = nextPairs
 		}
+		sh.lock.Unlock()
+	}
+}
+
+// GetCount sums the per-shard atomic counters instead of holding any lock.
+func (hm *HM) GetCount() int {
+	var total int64
+	for _, sh := range hm.shards {
+		total += atomic.LoadInt64(&sh.count)
+	}
+	return int(total)
+}
+
+// resolveForWrite must be called with sh.lock held for writing. If the
+// shard isn't resizing it just returns the live table; otherwise it
+// migrates the bucket key's hash falls into plus a bounded number of other
+// buckets, and returns whichever table now holds that bucket.
+func (sh *shard) resolveForWrite(hash uint64) ([]*Pairs, int) {
+	if sh.migrating {
+		sh.migrateBucket(int(hash & sh.mask))
+		sh.migrateSome(migrateStepsPerOp)
+		if sh.migrating {
+			return sh.slicesNew, int(hash & sh.maskNew)
+		}
+	}
+	return sh.slices, int(hash & sh.mask)
+}
+
+// startResize begins migrating sh into a table of newCapacity buckets.
+func (sh *shard) startResize(newCapacity int) {
+	sh.slicesNew = make([]*Pairs, newCapacity)
+	sh.capacityNew = newCapacity
+	sh.maskNew = uint64(newCapacity - 1)
+	sh.migrating = true
+	sh.migrateIdx = 0
+}
+
+func (sh *shard) maybeGrow(loadFactor float64) {
+	if sh.migrating {
+		return
+	}
+	if float64(atomic.LoadInt64(&sh.count)) > float64(sh.capacity)*loadFactor {
+		sh.startResize(sh.capacity * 2)
+	}
+}
+
+func (sh *shard) maybeShrink(loadFactor float64) {
+	if sh.migrating || sh.capacity <= 1<<4 {
+		return
+	}
+	if float64(atomic.LoadInt64(&sh.count)) < float64(sh.capacity)*loadFactor/4 {
+		sh.startResize(sh.capacity / 2)
+	}
+}
+
+// migrateBucket moves every entry in sh.slices[oldIndex] into sh.slicesNew,
+// using each Pairs' stored hash rather than recomputing it. It's a no-op if
+// the bucket was already migrated (or was always empty), so it's safe to
+// call out of order from both the on-demand and background paths.
+func (sh *shard) migrateBucket(oldIndex int) {
+	var pairs = sh.slices[oldIndex]
+	if pairs == nil {
+		return
+	}
+	sh.slices[oldIndex] = nil
+	for pairs != nil {
+		var next = pairs.next
+		pairs.last = nil
+		pairs.next = nil
+		appendPair(sh.slicesNew, int(pairs.hash&sh.maskNew), pairs)
+		pairs = next
+	}
+}
+
+// migrateSome migrates up to n more buckets from the background cursor,
+// finishing the resize once every bucket has moved.
+func (sh *shard) migrateSome(n int) {
+	for i := 0; i < n && sh.migrating; i++ {
+		if sh.migrateIdx >= sh.capacity {
+			sh.finishMigration()
+			break
+		}
+		sh.migrateBucket(sh.migrateIdx)
+		sh.migrateIdx++
 	}
 }
 
-func (hm *HM) getPairs(key interface{}) (*Pairs, int) {
-	hm.lock.RLock()
-	defer hm.lock.RUnlock()
-	return hm.getPairsUnsafe(key)
+// finishMigration drains any remaining buckets and swaps slicesNew in as
+// the live table.
+func (sh *shard) finishMigration() {
+	if !sh.migrating {
+		return
+	}
+	for sh.migrateIdx < sh.capacity {
+		sh.migrateBucket(sh.migrateIdx)
+		sh.migrateIdx++
+	}
+	sh.slices = sh.slicesNew
+	sh.capacity = sh.capacityNew
+	sh.mask = sh.maskNew
+	sh.slicesNew = nil
+	sh.capacityNew = 0
+	sh.migrating = false
+	sh.migrateIdx = 0
 }
 
-func (hm *HM) getPairsUnsafe(key interface{}) (*Pairs, int) {
-	var hashIndex, equal = hm.getHashIndexAndEqualFunc(key)
-	var pairs = hm.slices[hashIndex]
+func appendPair(table []*Pairs, hashIndex int, newPairs *Pairs) {
+	var pairs = table[hashIndex]
+	if pairs != nil {
+		pairs.last.next = newPairs
+		pairs.last = newPairs
+	} else {
+		newPairs.last = newPairs
+		table[hashIndex] = newPairs
+	}
+}
+
+// getPairsUnsafe only calls hasher.Equal on a stored-hash collision, since
+// two different keys almost never share a 64-bit hash.
+func (sh *shard) getPairsUnsafe(table []*Pairs, hashIndex int, hash uint64, hasher Hasher, key interface{}) *Pairs {
+	var pairs = table[hashIndex]
 	for {
 		if pairs == nil {
 			break
 		}
-		if equal(pairs.key, key) {
-			return pairs, hashIndex
+		if pairs.hash == hash && hasher.Equal(pairs.key, key) {
+			return pairs
 		}
 		pairs = pairs.next
 	}
-	return nil, hashIndex
+	return nil
 }
 
-func (hm *HM) setPairsEmpty(pairs *Pairs) {
+func (sh *shard) setPairsEmpty(pairs *Pairs) {
 	switch pairs.key.(type) {
 	case []uint8:
 		pairs.key = pairs.key.([]byte)[:0]
@@ -220,34 +462,29 @@ func (hm *HM) setPairsEmpty(pairs *Pairs) {
 	pairs.value = nil
 	pairs.last = nil
 	pairs.next = nil
+	pairs.lruPrev = nil
+	pairs.lruNext = nil
 }
 
-func (hm *HM) removePairs(key interface{}, updateFunc UpdateFunc) {
-	var hashIndex, equal = hm.getHashIndexAndEqualFunc(key)
+// removePairs finds key in table[hashIndex] and removes it. onRemoved, if
+// non-nil, is called with the node before updateFunc and before it's
+// cleared, so callers like HM.onRemoved can unlink it from the LRU list.
+func (sh *shard) removePairs(table []*Pairs, hashIndex int, hash uint64, hasher Hasher, key interface{}, updateFunc UpdateFunc, onRemoved func(*Pairs)) {
 	var prevPairs *Pairs = nil
-	var pairs = hm.slices[hashIndex]
+	var pairs = table[hashIndex]
 	var firstPairs = pairs
 	for {
 		if pairs == nil {
 			break
 		}
-		if equal(pairs.key, key) {
-			if prevPairs != nil {
-				prevPairs.next = pairs.next
-				if pairs.next == nil {
-					firstPairs.last = prevPairs
-				}
-			} else {
-				if pairs.next != nil {
-					pairs.next.last = pairs.last
-				}
-				hm.slices[hashIndex] = pairs.next
+		if pairs.hash == hash && hasher.Equal(pairs.key, key) {
+			if onRemoved != nil {
+				onRemoved(pairs)
 			}
 			if updateFunc != nil {
 				updateFunc(pairs.value)
 			}
-			hm.setPairsEmpty(pairs)
-			hm.count--
+			sh.unlinkPair(table, hashIndex, pairs, prevPairs, firstPairs)
 			break
 		}
 		prevPairs = pairs
@@ -255,49 +492,49 @@ func (hm *HM) removePairs(key interface{}, updateFunc UpdateFunc) {
 	}
 }
 
-func (hm *HM) GetCount() int {
-	hm.lock.RLock()
-	defer hm.lock.RUnlock()
-	return hm.count
-}
-
-func (hm *HM) getHashIndexAndEqualFunc(key interface{}) (int, EqualFunc) {
-	switch key.(type) {
-	case []uint8:
-		var hash = fnv.New32()
-		hash.Write(key.([]byte))
-		return int(hash.Sum32() & hm.mask_uint32), bytesEqual
-	case string:
-		var hash = fnv.New32()
-		hash.Write([]byte(key.(string)))
-		return int(hash.Sum32() & hm.mask_uint32), stringEqual
-	case int:
-		return key.(int) & hm.mask_int, intEqual
-	case uint64:
-		return int(key.(uint64) & hm.mask_uint64), uint64Equal
-	case uint32:
-		return int(key.(uint32) & hm.mask_uint32), uint32Equal
-	default:
-		panic(errors.New("bad key type"))
+// removePairsByNode removes target from table[hashIndex] by pointer
+// identity rather than a fresh hash/key lookup. evictIfOverCapacity picks
+// its victim from the LRU list without holding sh.lock, so by the time it
+// gets here a concurrent Remove may already have taken the same node out
+// from under it; only once target is found still linked in this shard's
+// table, under sh.lock, is it safe to read its key and value. notify, if
+// non-nil, is called with both right before the node is cleared.
+func (sh *shard) removePairsByNode(table []*Pairs, hashIndex int, target *Pairs, notify func(key, value interface{})) {
+	var prevPairs *Pairs = nil
+	var pairs = table[hashIndex]
+	var firstPairs = pairs
+	for {
+		if pairs == nil {
+			break
+		}
+		if pairs == target {
+			if notify != nil {
+				notify(pairs.key, pairs.value)
+			}
+			sh.unlinkPair(table, hashIndex, pairs, prevPairs, firstPairs)
+			break
+		}
+		prevPairs = pairs
+		pairs = pairs.next
 	}
 }
 
-func bytesEqual(v1, v2 interface{}) bool {
-	return bytes.Equal(v1.([]byte), v2.([]byte))
-}
-
-func stringEqual(v1, v2 interface{}) bool {
-	return v1.(string) == v2.(string)
-}
-
-func intEqual(v1, v2 interface{}) bool {
-	return v1.(int) == v2.(int)
-}
-
-func uint32Equal(v1, v2 interface{}) bool {
-	return v1.(uint32) == v2.(uint32)
-}
-
-func uint64Equal(v1, v2 interface{}) bool {
-	return v1.(uint64) == v2.(uint64)
+// unlinkPair splices pairs out of table[hashIndex], given the node
+// immediately before it (prevPairs, nil if pairs was the bucket head) and
+// the bucket's original head (firstPairs, which tracks the tail via
+// last), then clears it and updates the shard's live count.
+func (sh *shard) unlinkPair(table []*Pairs, hashIndex int, pairs, prevPairs, firstPairs *Pairs) {
+	if prevPairs != nil {
+		prevPairs.next = pairs.next
+		if pairs.next == nil {
+			firstPairs.last = prevPairs
+		}
+	} else {
+		if pairs.next != nil {
+			pairs.next.last = pairs.last
+		}
+		table[hashIndex] = pairs.next
+	}
+	sh.setPairsEmpty(pairs)
+	atomic.AddInt64(&sh.count, -1)
 }
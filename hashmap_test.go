@@ -0,0 +1,144 @@
+package hashmap
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestConcurrentGetPut stresses Get and Put on the same key from multiple
+// goroutines. Run with -race: a plain sharded map (no LRU) must never race
+// on a Pairs' value/expiry fields, since Get only reads them under sh.lock.
+func TestConcurrentGetPut(t *testing.T) {
+	var hm = New(16)
+	hm.Put("key", 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				hm.Put("key", n*1000+j)
+			}
+		}(i)
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				hm.Get("key")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestLRUConcurrentEvict stresses Put, Remove and Get on the same key of an
+// LRU-enabled map. Run with -race: evictIfOverCapacity must not read a
+// Pairs' key/hash outside the lock that guards their mutation by a
+// concurrent Remove.
+func TestLRUConcurrentEvict(t *testing.T) {
+	var hm = NewLRU(16, 4)
+	for i := 0; i < 8; i++ {
+		hm.Put(i, i)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			hm.Put(0, i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			hm.Remove(0)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			hm.Get(0)
+		}
+	}()
+	wg.Wait()
+}
+
+// TestGrowShrinkPreservesData drives a single-shard map through several
+// incremental grows (Put past the load factor) and shrinks (Remove below
+// the low-water mark), checking every surviving key at each end against
+// the migrateBucket/migrateSome cursor logic.
+func TestGrowShrinkPreservesData(t *testing.T) {
+	var hm = NewSharded(16, 1)
+	const n = 200
+	for i := 0; i < n; i++ {
+		hm.Put(i, i*i)
+	}
+	if got := hm.GetCount(); got != n {
+		t.Fatalf("GetCount() = %d, want %d", got, n)
+	}
+	for i := 0; i < n; i++ {
+		if v := hm.Get(i); v != i*i {
+			t.Fatalf("Get(%d) = %v, want %d", i, v, i*i)
+		}
+	}
+
+	for i := 0; i < n-2; i++ {
+		hm.Remove(i)
+	}
+	if got, want := hm.GetCount(), 2; got != want {
+		t.Fatalf("GetCount() after shrink = %d, want %d", got, want)
+	}
+	for i := n - 2; i < n; i++ {
+		if v := hm.Get(i); v != i*i {
+			t.Fatalf("Get(%d) after shrink = %v, want %d", i, v, i*i)
+		}
+	}
+	for i := 0; i < n-2; i++ {
+		if hm.Exists(i) {
+			t.Fatalf("Exists(%d) after shrink = true, want removed", i)
+		}
+	}
+}
+
+// TestUpdateWithFuncRemove exercises UpdateWithFunc's remove closure: a key
+// removed from inside its own callback must be gone afterward, and doing so
+// concurrently with Get/Put traffic on an unrelated key must not race.
+// Before this fix, the equivalent was RemoveUnsafe(key), which skipped
+// locking even when key's shard wasn't the one already held — this test
+// used to catch that as a -race failure when "target" and "other" land on
+// different shards.
+func TestUpdateWithFuncRemove(t *testing.T) {
+	var hm = New(16)
+	hm.Put("target", 1)
+	hm.Put("other", 2)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			hm.Put("other", i)
+			hm.Get("other")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			hm.Put("target", i)
+			hm.UpdateWithFunc("target", func(value interface{}, remove func()) {
+				if value != nil {
+					remove()
+				}
+			})
+		}
+	}()
+	wg.Wait()
+
+	if hm.Exists("target") {
+		t.Fatalf("expected target to be removed by its own UpdateWithFunc callback")
+	}
+}
@@ -0,0 +1,192 @@
+package hashmap
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// NewLRU creates a map that evicts its least-recently-used entry whenever
+// Put would otherwise grow past maxEntries. Recency is tracked across every
+// shard by threading Pairs.lruPrev/lruNext into one global list, maintained
+// by Get/Put/Exists and unwound by every removal path.
+func NewLRU(capacity int, maxEntries int) *HM {
+	var hm = New(capacity)
+	hm.maxEntries = maxEntries
+	return hm
+}
+
+func (hm *HM) lruEnabled() bool {
+	return hm.maxEntries > 0
+}
+
+// expired reports whether a TTL set by PutWithTTL has passed. A zero expiry
+// means the entry never expires.
+func (pairs *Pairs) expired() bool {
+	return pairs.expiry != 0 && time.Now().UnixNano() > pairs.expiry
+}
+
+// touchLRU moves pairs to the front of the recency list. It's a no-op
+// unless the map was created with NewLRU.
+func (hm *HM) touchLRU(pairs *Pairs) {
+	if !hm.lruEnabled() || pairs == nil {
+		return
+	}
+	hm.lruLock.Lock()
+	hm.unlinkLRU(pairs)
+	hm.pushFrontLRU(pairs)
+	hm.lruLock.Unlock()
+}
+
+// onRemoved is passed to shard.removePairs as the hook that keeps the LRU
+// list consistent with the removal paths that go through it (Remove,
+// RemoveAndUpdate, UpdateWithFunc's remove, IterateAndUpdate and the TTL
+// janitor); eviction unlinks its victim itself before removing it. It does
+// nothing unless the map was created with NewLRU.
+func (hm *HM) onRemoved(pairs *Pairs) {
+	if !hm.lruEnabled() {
+		return
+	}
+	hm.lruLock.Lock()
+	hm.unlinkLRU(pairs)
+	hm.lruLock.Unlock()
+}
+
+// evictIfOverCapacity drops the least-recently-used entry, if any, once the
+// map holds more than maxEntries. It's a no-op unless the map was created
+// with NewLRU.
+func (hm *HM) evictIfOverCapacity() {
+	if !hm.lruEnabled() || hm.GetCount() <= hm.maxEntries {
+		return
+	}
+	hm.lruLock.Lock()
+	var victim = hm.lruTail
+	if victim != nil {
+		hm.unlinkLRU(victim)
+	}
+	hm.lruLock.Unlock()
+	if victim == nil {
+		return
+	}
+	var sh = hm.shards[victim.hash>>hm.shardShift]
+	sh.lock.Lock()
+	var table, hashIndex = sh.resolveForWrite(victim.hash)
+	sh.removePairsByNode(table, hashIndex, victim, hm.notifyEvicted)
+	sh.lock.Unlock()
+}
+
+// notifyEvict returns an UpdateFunc that reports an eviction of key through
+// OnEvict's callback, if one is registered.
+func (hm *HM) notifyEvict(key interface{}) UpdateFunc {
+	return func(value interface{}) {
+		hm.notifyEvicted(key, value)
+	}
+}
+
+// notifyEvicted reports an eviction of key/value through OnEvict's
+// callback, if one is registered.
+func (hm *HM) notifyEvicted(key interface{}, value interface{}) {
+	if cb := hm.getOnEvict(); cb != nil {
+		cb(key, value)
+	}
+}
+
+func (hm *HM) unlinkLRU(pairs *Pairs) {
+	if pairs.lruPrev != nil {
+		pairs.lruPrev.lruNext = pairs.lruNext
+	} else if hm.lruHead == pairs {
+		hm.lruHead = pairs.lruNext
+	}
+	if pairs.lruNext != nil {
+		pairs.lruNext.lruPrev = pairs.lruPrev
+	} else if hm.lruTail == pairs {
+		hm.lruTail = pairs.lruPrev
+	}
+	pairs.lruPrev = nil
+	pairs.lruNext = nil
+}
+
+func (hm *HM) pushFrontLRU(pairs *Pairs) {
+	pairs.lruNext = hm.lruHead
+	if hm.lruHead != nil {
+		hm.lruHead.lruPrev = pairs
+	}
+	hm.lruHead = pairs
+	if hm.lruTail == nil {
+		hm.lruTail = pairs
+	}
+}
+
+// OnEvict registers a callback invoked whenever an entry is evicted for
+// being the least-recently-used one over maxEntries, or reaped by the
+// janitor for having expired. It replaces any previously registered
+// callback.
+func (hm *HM) OnEvict(f func(key interface{}, value interface{})) {
+	hm.onEvictLock.Lock()
+	hm.onEvictFn = f
+	hm.onEvictLock.Unlock()
+}
+
+func (hm *HM) getOnEvict() func(key interface{}, value interface{}) {
+	hm.onEvictLock.RLock()
+	defer hm.onEvictLock.RUnlock()
+	return hm.onEvictFn
+}
+
+// StartJanitor starts a background goroutine that walks every shard every
+// interval, dropping entries whose PutWithTTL deadline has passed. Calling
+// it more than once on the same map is a no-op. Stop it with StopJanitor.
+func (hm *HM) StartJanitor(interval time.Duration) {
+	if !atomic.CompareAndSwapInt32(&hm.janitorStarted, 0, 1) {
+		return
+	}
+	hm.janitorStop = make(chan struct{})
+	go hm.runJanitor(interval, hm.janitorStop)
+}
+
+// StopJanitor stops a goroutine started by StartJanitor. It's a no-op if
+// the janitor was never started.
+func (hm *HM) StopJanitor() {
+	if !atomic.CompareAndSwapInt32(&hm.janitorStarted, 1, 0) {
+		return
+	}
+	close(hm.janitorStop)
+}
+
+func (hm *HM) runJanitor(interval time.Duration, stop chan struct{}) {
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			hm.sweepExpired()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// sweepExpired walks every shard, in the same shape as IterateAndUpdate,
+// removing any Pairs whose TTL has passed.
+func (hm *HM) sweepExpired() {
+	var pairs, nextPairs *Pairs
+	for _, sh := range hm.shards {
+		sh.lock.Lock()
+		sh.finishMigration()
+		for _, pairs = range sh.slices {
+			if pairs == nil {
+				continue
+			}
+			for {
+				nextPairs = pairs.next
+				if pairs.expired() {
+					sh.removePairs(sh.slices, int(pairs.hash&sh.mask), pairs.hash, hm.hasher, pairs.key, hm.notifyEvict(pairs.key), hm.onRemoved)
+				}
+				if nextPairs == nil {
+					break
+				}
+				pairs = nextPairs
+			}
+		}
+		sh.lock.Unlock()
+	}
+}
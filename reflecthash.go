@@ -0,0 +1,127 @@
+package hashmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/fnv"
+	"math"
+	"reflect"
+)
+
+// appendToer lets a key type hash itself: if AppendTo is implemented,
+// ReflectHasher appends its output to the running hash instead of walking
+// the value's fields.
+type appendToer interface {
+	AppendTo([]byte) []byte
+}
+
+// ReflectHasher hashes arbitrary, possibly-struct keys by walking them with
+// reflection in a manner similar to tailscale's deephash: struct fields are
+// visited in declared order, numeric types contribute their raw bits,
+// slices and strings are length-prefixed, and a type implementing
+// AppendTo([]byte) []byte is hashed from that output instead of its fields.
+// Equal falls back to reflect.DeepEqual, so ReflectHasher is the right
+// choice for keys that aren't one of the types New's default FNVHasher
+// supports.
+type ReflectHasher struct{}
+
+func (ReflectHasher) Hash(key interface{}) uint64 {
+	var h = fnv.New64a()
+	hashValue(h, reflect.ValueOf(key))
+	return h.Sum64()
+}
+
+func (ReflectHasher) Equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func hashValue(h hash.Hash64, v reflect.Value) {
+	if !v.IsValid() {
+		h.Write([]byte{0})
+		return
+	}
+	if v.CanInterface() {
+		if at, ok := v.Interface().(appendToer); ok {
+			writeLengthPrefixed(h, at.AppendTo(nil))
+			return
+		}
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			h.Write([]byte{0})
+			return
+		}
+		h.Write([]byte{1})
+		hashValue(h, v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			h.Write([]byte{0})
+			return
+		}
+		h.Write([]byte{1})
+		hashValue(h, v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			hashValue(h, v.Field(i))
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i))
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			h.Write([]byte{0})
+			return
+		}
+		h.Write([]byte{1})
+		writeUint64(h, uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i))
+		}
+	case reflect.String:
+		writeLengthPrefixed(h, []byte(v.String()))
+	case reflect.Bool:
+		if v.Bool() {
+			h.Write([]byte{1})
+		} else {
+			h.Write([]byte{0})
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint64(h, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint64(h, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeUint64(h, math.Float64bits(v.Float()))
+	case reflect.Map:
+		// Map iteration order isn't stable, so entries are combined with
+		// XOR instead of being fed to h in whatever order they come back.
+		if v.IsNil() {
+			h.Write([]byte{0})
+			return
+		}
+		h.Write([]byte{1})
+		var acc uint64
+		for _, k := range v.MapKeys() {
+			var entry = fnv.New64a()
+			hashValue(entry, k)
+			hashValue(entry, v.MapIndex(k))
+			acc ^= entry.Sum64()
+		}
+		writeUint64(h, acc)
+	default:
+		panic(errors.New("reflecthasher: unsupported key field kind " + v.Kind().String()))
+	}
+}
+
+func writeUint64(h hash.Hash64, x uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], x)
+	h.Write(b[:])
+}
+
+func writeLengthPrefixed(h hash.Hash64, b []byte) {
+	writeUint64(h, uint64(len(b)))
+	h.Write(b)
+}
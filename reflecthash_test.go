@@ -0,0 +1,61 @@
+package hashmap
+
+import "testing"
+
+type reflectHashKey struct {
+	Name string
+	Age  int
+}
+
+type appendToKey struct {
+	id int
+}
+
+func (k appendToKey) AppendTo(b []byte) []byte {
+	return append(b, byte(k.id))
+}
+
+func TestReflectHasherStructKey(t *testing.T) {
+	var h = ReflectHasher{}
+	var a = reflectHashKey{Name: "ada", Age: 36}
+	var b = reflectHashKey{Name: "ada", Age: 36}
+	var other = reflectHashKey{Name: "ada", Age: 37}
+
+	if !h.Equal(a, b) {
+		t.Fatalf("Equal(%v, %v) = false, want true", a, b)
+	}
+	if h.Hash(a) != h.Hash(b) {
+		t.Fatalf("Hash(%v) != Hash(%v), want equal", a, b)
+	}
+	if h.Equal(a, other) {
+		t.Fatalf("Equal(%v, %v) = true, want false", a, other)
+	}
+	if h.Hash(a) == h.Hash(other) {
+		t.Fatalf("Hash(%v) == Hash(%v), want different", a, other)
+	}
+}
+
+func TestReflectHasherAppendTo(t *testing.T) {
+	var h = ReflectHasher{}
+	var a = appendToKey{id: 1}
+	var b = appendToKey{id: 1}
+	var other = appendToKey{id: 2}
+
+	if h.Hash(a) != h.Hash(b) {
+		t.Fatalf("Hash(%v) != Hash(%v), want equal", a, b)
+	}
+	if h.Hash(a) == h.Hash(other) {
+		t.Fatalf("Hash(%v) == Hash(%v), want different", a, other)
+	}
+}
+
+func TestReflectHasherInMap(t *testing.T) {
+	var hm = NewWithHasher(16, ReflectHasher{})
+	var k1 = reflectHashKey{Name: "ada", Age: 36}
+	var k2 = reflectHashKey{Name: "ada", Age: 36}
+
+	hm.Put(k1, "lovelace")
+	if v := hm.Get(k2); v != "lovelace" {
+		t.Fatalf("Get(%v) = %v, want %q", k2, v, "lovelace")
+	}
+}